@@ -0,0 +1,91 @@
+// Command accrualstub runs the accrual service stub from internal/accrualstub as a standalone server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/skaurus/yandex-practicum-go-exam/internal/accrualstub"
+)
+
+type config struct {
+	runAddress string
+	scriptPath string
+	rateLimit  int
+}
+
+// parseConfig reads flags first and lets environment variables override them,
+// the same precedence the specification uses for gophermart itself. The variables are
+// namespaced so that the stub never picks up gophermart's own RUN_ADDRESS.
+func parseConfig() (config, error) {
+	var cfg config
+	flag.StringVar(&cfg.runAddress, "a", "localhost:8081", "address and port to listen on")
+	flag.StringVar(&cfg.scriptPath, "s", "", "path to a JSON file with scripted responses")
+	flag.IntVar(&cfg.rateLimit, "l", 0, "max requests per minute, 0 means unlimited")
+	flag.Parse()
+
+	if v, ok := os.LookupEnv("ACCRUAL_STUB_ADDRESS"); ok {
+		cfg.runAddress = v
+	}
+	if v, ok := os.LookupEnv("ACCRUAL_STUB_SCRIPT"); ok {
+		cfg.scriptPath = v
+	}
+	if v, ok := os.LookupEnv("ACCRUAL_STUB_RATE_LIMIT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, errors.New("ACCRUAL_STUB_RATE_LIMIT must be an integer")
+		}
+		cfg.rateLimit = n
+	}
+	if cfg.rateLimit < 0 {
+		return cfg, errors.New("rate limit can't be negative")
+	}
+
+	return cfg, nil
+}
+
+func main() {
+	cfg, err := parseConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := accrualstub.DefaultScript()
+	if cfg.scriptPath != "" {
+		s, err = accrualstub.LoadScript(cfg.scriptPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.runAddress,
+		Handler:           accrualstub.NewHandler(s, accrualstub.NewLimiter(cfg.rateLimit, time.Minute)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("accrual stub listening on %s", cfg.runAddress)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
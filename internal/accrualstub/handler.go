@@ -0,0 +1,120 @@
+package accrualstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ordersPath = "/api/orders/"
+
+type orderResponse struct {
+	Order   string      `json:"order"`
+	Status  string      `json:"status"`
+	Accrual json.Number `json:"accrual,omitempty"`
+}
+
+// Limiter is a fixed-window request counter. A zero limit disables it.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	started time.Time
+	count   int
+}
+
+// NewLimiter allows up to limit requests per window.
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window}
+}
+
+// allow reports whether the request fits into the current window; if not, it also
+// returns how long the caller should wait before retrying.
+func (l *Limiter) allow(now time.Time) (bool, time.Duration) {
+	if l.limit == 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.started) >= l.window {
+		l.started = now
+		l.count = 0
+	}
+	if l.count >= l.limit {
+		return false, l.started.Add(l.window).Sub(now)
+	}
+	l.count++
+
+	return true, 0
+}
+
+// writeTooManyRequests sends a 429 in the format from the specification. Without a configured
+// limit there is no N to report, so the body falls back to the status text.
+func writeTooManyRequests(w http.ResponseWriter, retryAfter int, limit int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if limit > 0 {
+		fmt.Fprintf(w, "No more than %d requests per minute allowed", limit)
+	} else {
+		fmt.Fprint(w, http.StatusText(http.StatusTooManyRequests))
+	}
+}
+
+// NewHandler serves GET /api/orders/{number} from s, rate limited by l.
+func NewHandler(s *Script, l *Limiter) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ordersPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		number := strings.TrimPrefix(r.URL.Path, ordersPath)
+		if number == "" || strings.Contains(number, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		if ok, wait := l.allow(time.Now()); !ok {
+			writeTooManyRequests(w, int(math.Ceil(wait.Seconds())), l.limit)
+			return
+		}
+
+		st := s.next(number)
+		switch {
+		case st.Code == http.StatusTooManyRequests:
+			retryAfter := st.RetryAfter
+			if retryAfter == 0 {
+				retryAfter = defaultRetryAfter
+			}
+			writeTooManyRequests(w, retryAfter, l.limit)
+			return
+		case st.Code != 0 && st.Code != http.StatusOK:
+			w.WriteHeader(st.Code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(orderResponse{
+			Order:   number,
+			Status:  st.Status,
+			Accrual: st.Accrual,
+		})
+		if err != nil {
+			log.Printf("can't write response for order %s: %v", number, err)
+		}
+	})
+
+	return mux
+}
@@ -0,0 +1,211 @@
+package accrualstub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, srv *httptest.Server, number string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + ordersPath + number)
+	if err != nil {
+		t.Fatalf("GET %s: %v", number, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body for %s: %v", number, err)
+	}
+
+	return resp, string(body)
+}
+
+func getOrder(t *testing.T, srv *httptest.Server, number string) orderResponse {
+	t.Helper()
+
+	resp, body := get(t, srv, number)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("order %s: got status %d, want 200", number, resp.StatusCode)
+	}
+
+	var o orderResponse
+	if err := json.Unmarshal([]byte(body), &o); err != nil {
+		t.Fatalf("order %s: can't decode %q: %v", number, body, err)
+	}
+
+	return o
+}
+
+func newTestServer(t *testing.T, script string, l *Limiter) *httptest.Server {
+	t.Helper()
+
+	s, err := ParseScript([]byte(script))
+	if err != nil {
+		t.Fatalf("ParseScript: %v", err)
+	}
+	if l == nil {
+		l = NewLimiter(0, time.Minute)
+	}
+
+	srv := httptest.NewServer(NewHandler(s, l))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestHandlerOrderProgression(t *testing.T) {
+	srv := newTestServer(t, `{"orders": {"12345678903": [
+		{"status": "REGISTERED"},
+		{"status": "PROCESSING"},
+		{"status": "PROCESSED", "accrual": 729.98}
+	]}}`, nil)
+
+	want := []orderResponse{
+		{Order: "12345678903", Status: StatusRegistered},
+		{Order: "12345678903", Status: StatusProcessing},
+		{Order: "12345678903", Status: StatusProcessed, Accrual: "729.98"},
+		// The last step keeps repeating once the sequence is exhausted.
+		{Order: "12345678903", Status: StatusProcessed, Accrual: "729.98"},
+		{Order: "12345678903", Status: StatusProcessed, Accrual: "729.98"},
+	}
+	for i, w := range want {
+		if got := getOrder(t, srv, "12345678903"); got != w {
+			t.Errorf("request %d: got %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestHandlerUnknownOrderFollowsDefault(t *testing.T) {
+	srv := newTestServer(t, `{
+		"orders": {"12345678903": [{"status": "INVALID"}]},
+		"default": [{"status": "PROCESSING"}, {"status": "PROCESSED", "accrual": 500}]
+	}`, nil)
+
+	want := []orderResponse{
+		{Order: "79927398713", Status: StatusProcessing},
+		{Order: "79927398713", Status: StatusProcessed, Accrual: "500"},
+	}
+	for i, w := range want {
+		if got := getOrder(t, srv, "79927398713"); got != w {
+			t.Errorf("request %d: got %+v, want %+v", i, got, w)
+		}
+	}
+
+	// Each unknown order has its own position in the default sequence.
+	w := orderResponse{Order: "2377225624", Status: StatusProcessing}
+	if got := getOrder(t, srv, "2377225624"); got != w {
+		t.Errorf("second unknown order: got %+v, want %+v", got, w)
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	const window = 200 * time.Millisecond
+	srv := newTestServer(t, `{}`, NewLimiter(2, window))
+
+	for i := 0; i < 2; i++ {
+		if resp, _ := get(t, srv, "1"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want 200", i, resp.StatusCode)
+		}
+	}
+
+	resp, body := get(t, srv, "1")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: got status %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After: got %q, want %q", got, "1")
+	}
+	if want := "No more than 2 requests per minute allowed"; body != want {
+		t.Errorf("body: got %q, want %q", body, want)
+	}
+
+	time.Sleep(window + 50*time.Millisecond)
+	if resp, _ := get(t, srv, "1"); resp.StatusCode != http.StatusOK {
+		t.Errorf("next window: got status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHandlerScriptedCodes(t *testing.T) {
+	srv := newTestServer(t, `{"orders": {"1": [
+		{"code": 500},
+		{"code": 429, "retry_after": 5},
+		{"code": 429},
+		{"code": 204}
+	]}}`, nil)
+
+	resp, _ := get(t, srv, "1")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("step 0: got status %d, want 500", resp.StatusCode)
+	}
+
+	for _, retryAfter := range []string{"5", "60"} {
+		resp, body := get(t, srv, "1")
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("scripted 429: got status %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Retry-After"); got != retryAfter {
+			t.Errorf("scripted 429: Retry-After got %q, want %q", got, retryAfter)
+		}
+		if body == "" {
+			t.Error("scripted 429: empty body")
+		}
+	}
+
+	resp, _ = get(t, srv, "1")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("last step: got status %d, want 204", resp.StatusCode)
+	}
+}
+
+func TestParseScriptRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "unknown status",
+			script: `{"orders": {"1": [{"status": "NEW"}]}}`,
+			want:   `unknown status "NEW"`,
+		},
+		{
+			name:   "accrual on non-processed status",
+			script: `{"orders": {"1": [{"status": "PROCESSING", "accrual": 10}]}}`,
+			want:   "accrual is only sent for PROCESSED",
+		},
+		{
+			name:   "empty steps",
+			script: `{"orders": {"1": []}}`,
+			want:   "order 1: no steps",
+		},
+		{
+			name:   "code out of range",
+			script: `{"orders": {"1": [{"code": 42}]}}`,
+			want:   "invalid HTTP code 42",
+		},
+		{
+			name:   "retry_after without 429",
+			script: `{"orders": {"1": [{"code": 500, "retry_after": 5}]}}`,
+			want:   "retry_after is only sent with code 429",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScript([]byte(tt.script))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package accrualstub implements a stand-in for the accrual service described in SPECIFICATION.md.
+// It serves GET /api/orders/{number} from a scripted set of responses, so end-to-end flows can be
+// run locally or in tests without the proprietary accrual binary.
+package accrualstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Statuses the accrual service may report for an order.
+const (
+	StatusRegistered = "REGISTERED"
+	StatusInvalid    = "INVALID"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+)
+
+// defaultRetryAfter is what a scripted 429 sends in Retry-After unless the step overrides it;
+// it matches the example in the specification.
+const defaultRetryAfter = 60
+
+// Step is one scripted reply. Code defaults to 200; any other code is sent with an empty body,
+// which lets a script simulate 204 or 500 responses. A 429 is sent the way the rate limiter
+// sends it, with RetryAfter seconds in the Retry-After header.
+type Step struct {
+	Code       int         `json:"code,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Accrual    json.Number `json:"accrual,omitempty"`
+	RetryAfter int         `json:"retry_after,omitempty"`
+}
+
+// Script maps order numbers to the sequence of replies returned for consecutive requests.
+// Once a sequence is exhausted its last step is repeated. Orders that are not listed
+// follow Default.
+type Script struct {
+	Orders  map[string][]Step `json:"orders"`
+	Default []Step            `json:"default"`
+
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+// DefaultScript walks every order through REGISTERED and PROCESSING to PROCESSED with an accrual of 100.
+func DefaultScript() *Script {
+	return &Script{
+		Default: []Step{
+			{Status: StatusRegistered},
+			{Status: StatusProcessing},
+			{Status: StatusProcessed, Accrual: "100"},
+		},
+		seen: make(map[string]int),
+	}
+}
+
+// LoadScript reads and validates a script from a JSON file.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read script: %w", err)
+	}
+
+	s, err := ParseScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// ParseScript decodes and validates a script. An empty Default is replaced with DefaultScript's.
+func ParseScript(data []byte) (*Script, error) {
+	s := &Script{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("can't parse: %w", err)
+	}
+	if len(s.Default) == 0 {
+		s.Default = DefaultScript().Default
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid: %w", err)
+	}
+	s.seen = make(map[string]int)
+
+	return s, nil
+}
+
+// Validate checks that every step can be sent as-is, so a bad script fails at startup
+// rather than on the first matching request.
+func (s *Script) Validate() error {
+	check := func(name string, steps []Step) error {
+		if len(steps) == 0 {
+			return fmt.Errorf("%s: no steps", name)
+		}
+		for i, st := range steps {
+			if st.Code != 0 && (st.Code < 100 || st.Code > 599) {
+				return fmt.Errorf("%s: step %d: invalid HTTP code %d", name, i, st.Code)
+			}
+			if st.RetryAfter != 0 && st.Code != http.StatusTooManyRequests {
+				return fmt.Errorf("%s: step %d: retry_after is only sent with code %d",
+					name, i, http.StatusTooManyRequests)
+			}
+			if st.RetryAfter < 0 {
+				return fmt.Errorf("%s: step %d: retry_after can't be negative", name, i)
+			}
+			if st.Code != 0 && st.Code != http.StatusOK {
+				continue
+			}
+			switch st.Status {
+			case StatusRegistered, StatusInvalid, StatusProcessing, StatusProcessed:
+			default:
+				return fmt.Errorf("%s: step %d: unknown status %q", name, i, st.Status)
+			}
+			if st.Accrual != "" && st.Status != StatusProcessed {
+				return fmt.Errorf("%s: step %d: accrual is only sent for %s", name, i, StatusProcessed)
+			}
+		}
+		return nil
+	}
+
+	if err := check("default", s.Default); err != nil {
+		return err
+	}
+	for number, steps := range s.Orders {
+		if err := check("order "+number, steps); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// next returns the reply for the given order and advances its position in the sequence.
+func (s *Script) next(number string) Step {
+	steps, ok := s.Orders[number]
+	if !ok {
+		steps = s.Default
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.seen[number]
+	if i < len(steps)-1 {
+		s.seen[number] = i + 1
+	} else {
+		i = len(steps) - 1
+	}
+
+	return steps[i]
+}